@@ -0,0 +1,215 @@
+package color
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// ColorLevel classifies how much color a terminal supports, from none at
+// all up to full 24-bit truecolor.
+type ColorLevel int
+
+const (
+	LevelNone      ColorLevel = iota // no color support, e.g. TERM=dumb
+	Level8                           // the 8 basic ANSI colors
+	Level16                          // 8 basic colors plus bright variants
+	Level256                         // xterm 256-color palette
+	LevelTrueColor                   // 24-bit RGB
+)
+
+// levelOverride, when non-nil, forces Level to always report its value
+// regardless of the environment. Set via SetLevel. It's an atomic.Pointer
+// rather than a plain *ColorLevel since it's read on every colorize() call
+// and can be set concurrently from another goroutine.
+var levelOverride atomic.Pointer[ColorLevel]
+
+// SetLevel forces Level to report l regardless of $TERM/$COLORTERM. Call
+// SetLevel(Level()) captured earlier to restore whatever was previously in
+// effect; there is no "unset" sentinel. Safe for concurrent use.
+func SetLevel(l ColorLevel) {
+	levelOverride.Store(&l)
+}
+
+// Level reports the color capability to render against: the value forced
+// via SetLevel if any, otherwise one detected from $TERM and $COLORTERM.
+func Level() ColorLevel {
+	if override := levelOverride.Load(); override != nil {
+		return *override
+	}
+	return detectLevel()
+}
+
+// detectLevel classifies the terminal from $TERM and $COLORTERM. It's a
+// heuristic over the common conventions rather than a full terminfo
+// database lookup, which covers the terminals callers actually hit in
+// practice (xterm, the 256color/truecolor variants, CI log collectors that
+// set TERM=dumb, Windows Terminal via COLORTERM, etc).
+func detectLevel() ColorLevel {
+	term := os.Getenv("TERM")
+	if term == "" || term == "dumb" {
+		return LevelNone
+	}
+
+	switch os.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return LevelTrueColor
+	}
+
+	switch {
+	case strings.Contains(term, "256color"):
+		return Level256
+	case strings.Contains(term, "color"),
+		strings.HasPrefix(term, "xterm"),
+		strings.HasPrefix(term, "screen"),
+		strings.HasPrefix(term, "rxvt"),
+		strings.HasPrefix(term, "vt100"),
+		strings.HasPrefix(term, "linux"):
+		return Level16
+	default:
+		return Level8
+	}
+}
+
+// renderColor downgrades spec to the given Level and returns the escape
+// sequence to emit, or "" if level is LevelNone.
+func renderColor(spec colorSpec, level ColorLevel) string {
+	kind := 38
+	if spec.target == "bg" {
+		kind = 48
+	}
+
+	switch level {
+	case LevelNone:
+		return ""
+	case LevelTrueColor:
+		if spec.truecolor {
+			return fmt.Sprintf("\033[%d;2;%d;%d;%dm", kind, spec.r, spec.g, spec.b)
+		}
+		return fmt.Sprintf("\033[%d;5;%dm", kind, spec.palette)
+	case Level256:
+		idx := spec.palette
+		if spec.truecolor {
+			idx = nearest256(spec.r, spec.g, spec.b)
+		}
+		return fmt.Sprintf("\033[%d;5;%dm", kind, idx)
+	default: // Level8, Level16
+		r, g, b := spec.r, spec.g, spec.b
+		if !spec.truecolor {
+			r, g, b = paletteToRGB(spec.palette)
+		}
+		base := 30
+		if spec.target == "bg" {
+			base = 40
+		}
+		idx := nearest8(r, g, b)
+		if level == Level16 {
+			var bright bool
+			if idx, bright = nearest16(r, g, b); bright {
+				base += 60
+			}
+		}
+		return fmt.Sprintf("\033[%dm", base+idx)
+	}
+}
+
+// nearest8 maps an RGB color to the index (0-7) of the closest basic ANSI
+// color, using the standard bit-per-channel encoding (bit0=red, bit1=green,
+// bit2=blue) that black/red/green/yellow/blue/magenta/cyan/white follow.
+func nearest8(r, g, b int) int {
+	idx := 0
+	if r > 127 {
+		idx |= 1
+	}
+	if g > 127 {
+		idx |= 2
+	}
+	if b > 127 {
+		idx |= 4
+	}
+	return idx
+}
+
+// nearest16 is like nearest8, but also reports whether the color is bright
+// enough to use the aixterm bright variant (codes 90-97/100-107) that
+// Level16 terminals support in addition to the 8 basic colors. A color
+// counts as bright when its most saturated channel is past the midpoint
+// between nearest8's on/off threshold (127) and full intensity (255), e.g.
+// #ffffff renders as bright white (97) rather than plain white (37).
+func nearest16(r, g, b int) (idx int, bright bool) {
+	idx = nearest8(r, g, b)
+	max := r
+	if g > max {
+		max = g
+	}
+	if b > max {
+		max = b
+	}
+	return idx, max > 192
+}
+
+// cubeLevels are the per-channel intensities of the xterm 256-color 6x6x6
+// RGB cube (indices 16-231).
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// nearest256 maps an RGB color to the closest xterm 256-color palette
+// index, picking between the grayscale ramp (232-255) and the color cube
+// (16-231).
+func nearest256(r, g, b int) int {
+	if r == g && g == b {
+		switch {
+		case r < 8:
+			return 16
+		case r > 248:
+			return 231
+		default:
+			return 232 + (r-8)*24/247
+		}
+	}
+	ri := closestCubeIndex(r)
+	gi := closestCubeIndex(g)
+	bi := closestCubeIndex(b)
+	return 16 + 36*ri + 6*gi + bi
+}
+
+func closestCubeIndex(v int) int {
+	best, bestDiff := 0, 1<<30
+	for i, level := range cubeLevels {
+		diff := v - level
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < bestDiff {
+			best, bestDiff = i, diff
+		}
+	}
+	return best
+}
+
+// ansi16 holds the conventional RGB values of the 16 basic xterm colors,
+// used to downgrade a palette index back to RGB when rendering for an
+// 8/16-color terminal.
+var ansi16 = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// paletteToRGB approximates the RGB value of an xterm 256-color palette
+// index, covering the 16 basic colors, the 6x6x6 cube and the grayscale
+// ramp.
+func paletteToRGB(idx int) (r, g, b int) {
+	switch {
+	case idx < 16:
+		c := ansi16[idx]
+		return c[0], c[1], c[2]
+	case idx < 232:
+		idx -= 16
+		return cubeLevels[idx/36], cubeLevels[(idx/6)%6], cubeLevels[idx%6]
+	default:
+		level := 8 + (idx-232)*10
+		return level, level, level
+	}
+}
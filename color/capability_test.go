@@ -0,0 +1,117 @@
+package color
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestNearest8(t *testing.T) {
+	cases := []struct {
+		r, g, b int
+		want    int
+	}{
+		{0, 0, 0, 0},
+		{255, 0, 0, 1},
+		{0, 255, 0, 2},
+		{255, 255, 0, 3},
+		{0, 0, 255, 4},
+		{255, 255, 255, 7},
+	}
+	for _, c := range cases {
+		if got := nearest8(c.r, c.g, c.b); got != c.want {
+			t.Errorf("nearest8(%d,%d,%d) = %d want %d", c.r, c.g, c.b, got, c.want)
+		}
+	}
+}
+
+func TestNearest16Bright(t *testing.T) {
+	idx, bright := nearest16(255, 255, 255)
+	if idx != 7 || !bright {
+		t.Errorf("nearest16(white) = %d,%v want 7,true", idx, bright)
+	}
+	idx, bright = nearest16(128, 0, 0)
+	if idx != 1 || bright {
+		t.Errorf("nearest16(dim red) = %d,%v want 1,false", idx, bright)
+	}
+}
+
+func TestNearest256(t *testing.T) {
+	cases := []struct {
+		r, g, b int
+		want    int
+	}{
+		{0, 0, 0, 16},
+		{255, 255, 255, 231},
+		{128, 128, 128, 232 + (128-8)*24/247},
+	}
+	for _, c := range cases {
+		if got := nearest256(c.r, c.g, c.b); got != c.want {
+			t.Errorf("nearest256(%d,%d,%d) = %d want %d", c.r, c.g, c.b, got, c.want)
+		}
+	}
+}
+
+func TestPaletteToRGBRoundTrip(t *testing.T) {
+	for _, idx := range []int{0, 15, 16, 196, 231, 232, 255} {
+		r, g, b := paletteToRGB(idx)
+		if r < 0 || r > 255 || g < 0 || g > 255 || b < 0 || b > 255 {
+			t.Errorf("paletteToRGB(%d) = %d,%d,%d out of range", idx, r, g, b)
+		}
+	}
+}
+
+func TestRenderColorByLevel(t *testing.T) {
+	spec := colorSpec{target: "fg", truecolor: true, r: 0xff, g: 0x88, b: 0x00}
+
+	cases := []struct {
+		level ColorLevel
+		want  string
+	}{
+		{LevelNone, ""},
+		{Level8, "\033[33m"},
+		{Level16, "\033[93m"},
+		{Level256, "\033[38;5;208m"},
+		{LevelTrueColor, "\033[38;2;255;136;0m"},
+	}
+	for _, c := range cases {
+		if got := renderColor(spec, c.level); got != c.want {
+			t.Errorf("renderColor(%v) = %q want %q", c.level, got, c.want)
+		}
+	}
+}
+
+func TestLevel8And16Differ(t *testing.T) {
+	spec := colorSpec{target: "fg", truecolor: true, r: 255, g: 255, b: 255}
+	got8 := renderColor(spec, Level8)
+	got16 := renderColor(spec, Level16)
+	if got8 == got16 {
+		t.Errorf("Level8 and Level16 render identically: %q", got8)
+	}
+}
+
+func TestSetLevelOverride(t *testing.T) {
+	defer levelOverride.Store(nil)
+
+	SetLevel(LevelTrueColor)
+	if got := Level(); got != LevelTrueColor {
+		t.Errorf("Level() after SetLevel = %v want %v", got, LevelTrueColor)
+	}
+}
+
+func TestSetLevelConcurrent(t *testing.T) {
+	defer levelOverride.Store(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				SetLevel(Level256)
+			} else {
+				_ = Level()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
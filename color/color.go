@@ -25,12 +25,35 @@
 //      @{?} Reverse the foreground and background color
 //      @{-} Hide the text
 // Note some of the functions are not widely supported, like "Fast blink" and "Italic".
+//
+// A @{...} block also accepts comma-separated truecolor, 256-palette and
+// named specs alongside the shortcodes above:
+//      @{#ff8800}        24-bit RGB foreground
+//      @{bg:#003366}     24-bit RGB background
+//      @{fg:214}         xterm-256 palette foreground
+//      @{bg:238}         xterm-256 palette background
+//      @{@warning}       foreground set via RegisterNamed("warning", ...)
+//      @{bK,#ff8800}     shortcodes and rich specs can be combined
+//
+// Colorization is automatically disabled when the destination is not a
+// terminal (e.g. output redirected to a file or a pipe) or when the NO_COLOR
+// environment variable is set, and can be forced on or off with Disable. On
+// Windows, output written through Print/Println/Printf/Fprint*/Sprint* is
+// translated into Win32 console calls on consoles that don't understand raw
+// ANSI escape codes.
+//
+// Truecolor and 256-palette colors are automatically downgraded to match
+// what the terminal actually supports, as reported by Level; call SetLevel
+// to override the detected value.
 package color
 
 import (
 	"bytes"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync/atomic"
 )
 
 const (
@@ -39,7 +62,7 @@ const (
 )
 
 // Mapping from character to concrete escape code.
-var codeMap = map[byte]int{
+var codeMap = map[rune]int{
 	'|': 0,
 	'!': 1,
 	'.': 2,
@@ -71,32 +94,140 @@ var codeMap = map[byte]int{
 	'D': 49,
 }
 
+// disableOverride, when non-nil, forces colorization on (false) or off
+// (true) regardless of what the destination writer looks like. Set via
+// Disable. It's an atomic.Pointer rather than a plain *bool since it's read
+// on every Print/Sprint/Write call and can be set concurrently from another
+// goroutine.
+var disableOverride atomic.Pointer[bool]
+
+// Disable forces colorization on or off for every function in this package,
+// overriding both the automatic terminal detection and NO_COLOR. Call
+// Disable(false) to go back to automatic detection. Safe for concurrent
+// use.
+func Disable(disable bool) {
+	disableOverride.Store(&disable)
+}
+
+// noColorEnv reports whether the NO_COLOR environment variable is set, per
+// the https://no-color.org convention.
+func noColorEnv() bool {
+	_, ok := os.LookupEnv("NO_COLOR")
+	return ok
+}
+
+// shouldColorize reports whether output bound for w should carry color
+// escape codes. A nil w (used by the Sprint family, which has no
+// destination to inspect) is colorized unless explicitly overridden.
+func shouldColorize(w io.Writer) bool {
+	if override := disableOverride.Load(); override != nil {
+		return !*override
+	}
+	if noColorEnv() {
+		return false
+	}
+	if w == nil {
+		return true
+	}
+	return isTerminalWriter(w)
+}
+
+// willColorize is shouldColorize plus the Level()==LevelNone case (e.g.
+// TERM=dumb on an otherwise real terminal): it's the single source of truth
+// for whether a caller should append ResetCode or other raw escape bytes of
+// its own, since compile/colorize already consult it for the @{} syntax.
+func willColorize(w io.Writer) bool {
+	return shouldColorize(w) && Level() != LevelNone
+}
+
+// isTerminalWriter reports whether w refers to a terminal device.
+func isTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	return isTerminal(f.Fd())
+}
+
 // Compile color syntax string like "rG" to escape code.
 func Colorize(x string) (result string, err error) {
+	return colorize(x, true)
+}
+
+// colorize is the implementation behind Colorize. x is a comma-separated
+// list of tokens: runs of the legacy single-letter shortcodes (e.g. "bK"),
+// or one of the richer specs handled by parseRichToken (truecolor,
+// 256-palette, or named colors). When enabled is false the syntax is still
+// validated, but the empty string is returned instead of an escape
+// sequence, so plain text is left untouched.
+func colorize(x string, enabled bool) (result string, err error) {
 	attr := 0
 	fg := 39
 	bg := 49
+	haveLegacy := false
+	var rich []colorSpec
 
-	for _, key := range x {
-		c, ok := codeMap[key]
-		switch {
-		case !ok:
-			err = fmt.Errorf("Wrong color syntax: %c", key)
+	for _, token := range strings.Split(x, ",") {
+		if token == "" {
+			continue
+		}
+
+		var spec colorSpec
+		var matched bool
+		if spec, matched, err = parseRichToken(token); err != nil {
 			return
-		case 0 <= c && c <= 8:
-			attr = c
-		case 30 <= c && c <= 37:
-			fg = c
-		case 40 <= c && c <= 47:
-			bg = c
 		}
+		if matched {
+			rich = append(rich, spec)
+			continue
+		}
+
+		haveLegacy = true
+		for _, key := range token {
+			c, ok := codeMap[key]
+			switch {
+			case !ok:
+				err = fmt.Errorf("Wrong color syntax: %c", key)
+				return
+			case 0 <= c && c <= 8:
+				attr = c
+			case 30 <= c && c <= 37:
+				fg = c
+			case 40 <= c && c <= 47:
+				bg = c
+			}
+		}
+	}
+
+	level := Level()
+	if level == LevelNone {
+		enabled = false
 	}
-	result = fmt.Sprintf("\033[%d;%d;%dm", attr, fg, bg)
+	if !enabled {
+		return
+	}
+
+	var buf strings.Builder
+	if haveLegacy || len(rich) == 0 {
+		buf.WriteString(legacySGR(attr, fg, bg))
+	}
+	for _, spec := range rich {
+		buf.WriteString(renderColor(spec, level))
+	}
+	result = buf.String()
 	return
 }
 
+// legacySGR renders the single-letter shortcode style/foreground/background
+// triple as a "\033[attr;fg;bgm" escape sequence. It's the one place that
+// format is built, shared by colorize (for the @{} syntax) and Style (for
+// the Attribute-based API), so the two stay in sync.
+func legacySGR(attr, fg, bg int) string {
+	return fmt.Sprintf("\033[%d;%d;%dm", attr, fg, bg)
+}
+
 // Handle state after meeting one '@'
-func compileColorSyntax(input, output *bytes.Buffer) (err error) {
+func compileColorSyntax(input, output *bytes.Buffer, enabled bool) (err error) {
 	i, _, err := input.ReadRune()
 	if err != nil {
 		return
@@ -105,7 +236,7 @@ func compileColorSyntax(input, output *bytes.Buffer) (err error) {
 	switch i {
 	default:
 		codes := ""
-		if codes, err = Colorize(string(i)); err != nil {
+		if codes, err = colorize(string(i), enabled); err != nil {
 			return
 		}
 		output.WriteString(codes)
@@ -122,7 +253,7 @@ func compileColorSyntax(input, output *bytes.Buffer) (err error) {
 			color.WriteRune(i)
 		}
 		codes := ""
-		if codes, err = Colorize(color.String()); err != nil {
+		if codes, err = colorize(color.String(), enabled); err != nil {
 			return
 		}
 		output.WriteString(codes)
@@ -132,8 +263,10 @@ func compileColorSyntax(input, output *bytes.Buffer) (err error) {
 	return
 }
 
-// Compile the string and replace color syntax with concrete escape code.
-func compile(x string) (result string, err error) {
+// Compile the string and replace color syntax with concrete escape code. If
+// enabled is false, color syntax is parsed and validated as usual but no
+// escape codes are emitted, leaving only the plain text.
+func compile(x string, enabled bool) (result string, err error) {
 	if x == "" {
 		result = ""
 		return
@@ -146,26 +279,29 @@ func compile(x string) (result string, err error) {
 	for {
 		i, _, err = input.ReadRune()
 		if err != nil {
-			return
+			break
 		}
 		switch i {
 		default:
 			output.WriteRune(i)
 		case EscapeChar:
-			if err = compileColorSyntax(input, output); err != nil {
+			if err = compileColorSyntax(input, output, enabled); err != nil {
 				return
 			}
 		}
 	}
+	if err == io.EOF {
+		err = nil
+	}
 	result = output.String()
 	return
 }
 
 // Compile multiple values, only do compiling on string type.
-func compileValues(a *[]interface{}) (err error) {
+func compileValues(a *[]interface{}, enabled bool) (err error) {
 	for i, x := range *a {
 		if str, ok := x.(string); ok {
-			if (*a)[i], err = compile(str); err != nil {
+			if (*a)[i], err = compile(str, enabled); err != nil {
 				return
 			}
 		}
@@ -175,68 +311,92 @@ func compileValues(a *[]interface{}) (err error) {
 
 // Similar to fmt.Print, will reset the color at the end.
 func Print(a ...interface{}) (result int, err error) {
-	a = append(a, ResetCode)
-	if err = compileValues(&a); err != nil {
+	enabled := willColorize(os.Stdout)
+	if enabled {
+		a = append(a, ResetCode)
+	}
+	if err = compileValues(&a, enabled); err != nil {
 		return
 	}
-	return fmt.Print(a...)
+	return fmt.Fprint(stdout, a...)
 }
 
 // Similar to fmt.Println, will reset the color at the end.
 func Println(a ...interface{}) (result int, err error) {
-	a = append(a, ResetCode)
-	if err = compileValues(&a); err != nil {
+	enabled := willColorize(os.Stdout)
+	if enabled {
+		a = append(a, ResetCode)
+	}
+	if err = compileValues(&a, enabled); err != nil {
 		return
 	}
-	return fmt.Println(a...)
+	return fmt.Fprintln(stdout, a...)
 }
 
 // Similar to fmt.Printf, will reset the color at the end.
 func Printf(format string, a ...interface{}) (result int, err error) {
-	format += ResetCode
-	if format, err = compile(format); err != nil {
+	enabled := willColorize(os.Stdout)
+	if enabled {
+		format += ResetCode
+	}
+	if format, err = compile(format, enabled); err != nil {
 		return
 	}
-	return fmt.Printf(format, a...)
+	return fmt.Fprintf(stdout, format, a...)
 }
 
 // Similar to fmt.Fprint, will reset the color at the end.
 func Fprint(w io.Writer, a ...interface{}) (result int, err error) {
-	a = append(a, ResetCode)
-	if err = compileValues(&a); err != nil {
+	enabled := willColorize(w)
+	if enabled {
+		a = append(a, ResetCode)
+	}
+	if err = compileValues(&a, enabled); err != nil {
 		return
 	}
-	return fmt.Fprint(w, a...)
+	return fmt.Fprint(colorable(w), a...)
 }
 
 // Similar to fmt.Fprintln, will reset the color at the end.
 func Fprintln(w io.Writer, a ...interface{}) (result int, err error) {
-	a = append(a, ResetCode)
-	if err = compileValues(&a); err != nil {
+	enabled := willColorize(w)
+	if enabled {
+		a = append(a, ResetCode)
+	}
+	if err = compileValues(&a, enabled); err != nil {
 		return
 	}
-	return fmt.Fprintln(w, a...)
+	return fmt.Fprintln(colorable(w), a...)
 }
 
 // Similar to fmt.Fprintf, will reset the color at the end.
 func Fprintf(w io.Writer, format string, a ...interface{}) (result int, err error) {
-	format += ResetCode
-	if format, err = compile(format); err != nil {
+	enabled := willColorize(w)
+	if enabled {
+		format += ResetCode
+	}
+	if format, err = compile(format, enabled); err != nil {
 		return
 	}
-	return fmt.Fprintf(w, format, a...)
+	return fmt.Fprintf(colorable(w), format, a...)
 }
 
 // Similar to fmt.Sprint, will reset the color at the end.
 func Sprint(a ...interface{}) string {
-	a = append(a, ResetCode)
-	compileValues(&a)
+	enabled := willColorize(nil)
+	if enabled {
+		a = append(a, ResetCode)
+	}
+	compileValues(&a, enabled)
 	return fmt.Sprint(a...)
 }
 
 // Similar to fmt.Sprintf, will reset the color at the end.
 func Sprintf(format string, a ...interface{}) string {
-	format += ResetCode
-	format = compile(format)
+	enabled := willColorize(nil)
+	if enabled {
+		format += ResetCode
+	}
+	format, _ = compile(format, enabled)
 	return fmt.Sprintf(format, a...)
 }
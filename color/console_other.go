@@ -0,0 +1,12 @@
+//go:build !windows
+// +build !windows
+
+package color
+
+import "io"
+
+// colorable returns w unchanged: non-Windows terminals already understand
+// raw ANSI escape codes.
+func colorable(w io.Writer) io.Writer {
+	return w
+}
@@ -0,0 +1,215 @@
+//go:build windows
+// +build windows
+
+package color
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+var (
+	procSetConsoleTextAttribute    = kernel32.NewProc("SetConsoleTextAttribute")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+)
+
+// enableVirtualTerminalProcessing is the console mode flag that makes a
+// Windows console interpret ANSI/SGR escape codes natively (Windows
+// Terminal, ConPTY, and modern conhost.exe all support it).
+const enableVirtualTerminalProcessing = 0x0004
+
+type coord struct{ X, Y int16 }
+type smallRect struct{ Left, Top, Right, Bottom int16 }
+
+type consoleScreenBufferInfo struct {
+	Size              coord
+	CursorPosition    coord
+	Attributes        uint16
+	Window            smallRect
+	MaximumWindowSize coord
+}
+
+const (
+	foregroundBlue      = 0x0001
+	foregroundGreen     = 0x0002
+	foregroundRed       = 0x0004
+	foregroundIntensity = 0x0008
+	backgroundBlue      = 0x0010
+	backgroundGreen     = 0x0020
+	backgroundRed       = 0x0040
+	backgroundIntensity = 0x0080
+)
+
+// fgTable and bgTable map the SGR foreground/background codes this package
+// emits (30-37 and 40-47) to their Win32 console attribute bits.
+var fgTable = map[int]uint16{
+	30: 0, 31: foregroundRed, 32: foregroundGreen, 33: foregroundRed | foregroundGreen,
+	34: foregroundBlue, 35: foregroundRed | foregroundBlue, 36: foregroundGreen | foregroundBlue,
+	37: foregroundRed | foregroundGreen | foregroundBlue,
+}
+var bgTable = map[int]uint16{
+	40: 0, 41: backgroundRed, 42: backgroundGreen, 43: backgroundRed | backgroundGreen,
+	44: backgroundBlue, 45: backgroundRed | backgroundBlue, 46: backgroundGreen | backgroundBlue,
+	47: backgroundRed | backgroundGreen | backgroundBlue,
+}
+
+// colorable wraps w so that SGR escape codes written to it are translated
+// into Win32 console attribute changes, for consoles that don't interpret
+// ANSI natively. Writers that aren't a terminal *os.File, or that already
+// understand ANSI once ENABLE_VIRTUAL_TERMINAL_PROCESSING is turned on
+// (Windows Terminal, ConPTY, modern conhost.exe), pass through unchanged.
+func colorable(w io.Writer) io.Writer {
+	f, ok := w.(*os.File)
+	if !ok || !isTerminal(f.Fd()) {
+		return w
+	}
+	if enableNativeANSI(f.Fd()) {
+		return w
+	}
+	return &consoleWriter{f: f, attr: consoleAttributes(f.Fd()), reset: consoleAttributes(f.Fd())}
+}
+
+// enableNativeANSI tries to turn on ENABLE_VIRTUAL_TERMINAL_PROCESSING for
+// fd, reporting whether it succeeded. When it does, the console itself
+// renders raw ANSI escape codes, so there's nothing left for consoleWriter
+// to translate.
+func enableNativeANSI(fd uintptr) bool {
+	var mode uint32
+	if ret, _, _ := procGetConsoleMode.Call(fd, uintptr(unsafe.Pointer(&mode))); ret == 0 {
+		return false
+	}
+	ret, _, _ := procSetConsoleMode.Call(fd, uintptr(mode|enableVirtualTerminalProcessing))
+	return ret != 0
+}
+
+func consoleAttributes(fd uintptr) uint16 {
+	var info consoleScreenBufferInfo
+	procGetConsoleScreenBufferInfo.Call(fd, uintptr(unsafe.Pointer(&info)))
+	if info.Attributes == 0 {
+		return foregroundRed | foregroundGreen | foregroundBlue
+	}
+	return info.Attributes
+}
+
+// consoleWriter translates the ANSI SGR sequences this package emits into
+// SetConsoleTextAttribute calls as they stream through Write.
+type consoleWriter struct {
+	mu    sync.Mutex
+	f     *os.File
+	attr  uint16 // attributes currently applied to the console
+	reset uint16 // the console's attributes before we touched it
+}
+
+func (c *consoleWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(p)
+	for len(p) > 0 {
+		esc := bytes.IndexByte(p, '\033')
+		if esc < 0 {
+			c.f.Write(p)
+			break
+		}
+		if esc > 0 {
+			c.f.Write(p[:esc])
+			p = p[esc:]
+		}
+
+		if len(p) < 2 || p[1] != '[' {
+			p = p[1:]
+			continue
+		}
+		end := bytes.IndexByte(p, 'm')
+		if end < 0 {
+			p = p[1:]
+			continue
+		}
+		c.attr = applySGR(c.attr, string(p[2:end]), c.reset)
+		setConsoleTextAttribute(c.f.Fd(), c.attr)
+		p = p[end+1:]
+	}
+	return n, nil
+}
+
+func applySGR(attr uint16, params string, reset uint16) uint16 {
+	if params == "" {
+		params = "0"
+	}
+	parts := strings.Split(params, ";")
+	codes := make([]int, len(parts))
+	for i, part := range parts {
+		code, err := strconv.Atoi(part)
+		if err != nil {
+			code = -1
+		}
+		codes[i] = code
+	}
+
+	for i := 0; i < len(codes); i++ {
+		switch code := codes[i]; {
+		case code == 0:
+			attr = reset
+		case code == 1:
+			attr |= foregroundIntensity
+		case code == 7:
+			attr = swapForegroundBackground(attr)
+		case code >= 30 && code <= 37:
+			attr = attr&^uint16(foregroundRed|foregroundGreen|foregroundBlue) | fgTable[code]
+		case code == 38:
+			r, g, b, consumed := readExtendedColor(codes[i+1:])
+			attr = attr&^uint16(foregroundRed|foregroundGreen|foregroundBlue) | downgradeRGB(r, g, b, fgTable, 30)
+			i += consumed
+		case code == 39:
+			attr = attr&^uint16(foregroundRed|foregroundGreen|foregroundBlue) | reset&(foregroundRed|foregroundGreen|foregroundBlue)
+		case code >= 40 && code <= 47:
+			attr = attr&^uint16(backgroundRed|backgroundGreen|backgroundBlue) | bgTable[code]
+		case code == 48:
+			r, g, b, consumed := readExtendedColor(codes[i+1:])
+			attr = attr&^uint16(backgroundRed|backgroundGreen|backgroundBlue) | downgradeRGB(r, g, b, bgTable, 40)
+			i += consumed
+		case code == 49:
+			attr = attr&^uint16(backgroundRed|backgroundGreen|backgroundBlue) | reset&(backgroundRed|backgroundGreen|backgroundBlue)
+		}
+	}
+	return attr
+}
+
+// readExtendedColor parses the parameters that follow a 38/48 "extended
+// color" SGR code: either "5;N" (a 256-palette index) or "2;r;g;b" (24-bit
+// RGB). It returns the color in RGB and how many of rest were consumed, so
+// the caller can skip over them; a malformed sequence consumes nothing.
+func readExtendedColor(rest []int) (r, g, b, consumed int) {
+	if len(rest) >= 2 && rest[0] == 5 {
+		r, g, b = paletteToRGB(rest[1])
+		return r, g, b, 2
+	}
+	if len(rest) >= 4 && rest[0] == 2 {
+		return rest[1], rest[2], rest[3], 4
+	}
+	return 0, 0, 0, 0
+}
+
+// downgradeRGB maps an RGB color to the nearest of the 8 basic ANSI colors
+// and looks up its Win32 console attribute bits in table (fgTable/bgTable),
+// since legacy consoles can't render truecolor or 256-palette colors.
+func downgradeRGB(r, g, b int, table map[int]uint16, base int) uint16 {
+	return table[base+nearest8(r, g, b)]
+}
+
+func swapForegroundBackground(attr uint16) uint16 {
+	fg := attr & (foregroundRed | foregroundGreen | foregroundBlue | foregroundIntensity)
+	bg := attr & (backgroundRed | backgroundGreen | backgroundBlue | backgroundIntensity)
+	return attr&^uint16(foregroundRed|foregroundGreen|foregroundBlue|foregroundIntensity|
+		backgroundRed|backgroundGreen|backgroundBlue|backgroundIntensity) | fg<<4 | bg>>4
+}
+
+func setConsoleTextAttribute(fd uintptr, attr uint16) {
+	procSetConsoleTextAttribute.Call(fd, uintptr(attr))
+}
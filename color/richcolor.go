@@ -0,0 +1,106 @@
+package color
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	hexColorRE = regexp.MustCompile(`^(?:(fg|bg):)?#([0-9a-fA-F]{6})$`)
+	paletteRE  = regexp.MustCompile(`^(fg|bg):(\d{1,3})$`)
+	namedRE    = regexp.MustCompile(`^(?:(fg|bg):)?@([A-Za-z0-9_-]+)$`)
+)
+
+var (
+	namedColorsMu sync.RWMutex
+	namedColors   = map[string]string{}
+)
+
+// RegisterNamed associates name with a 24-bit RGB color given as a
+// "#rrggbb" string, making it usable inside @{} blocks as "@name" (for the
+// foreground) or "bg:@name" (for the background). It returns an error if
+// hex isn't a valid "#rrggbb" spec.
+func RegisterNamed(name, hex string) error {
+	if _, _, _, err := parseHex(hex); err != nil {
+		return err
+	}
+	namedColorsMu.Lock()
+	namedColors[name] = hex
+	namedColorsMu.Unlock()
+	return nil
+}
+
+// colorSpec is a parsed truecolor, 256-palette or named-color token from a
+// @{} block, not yet downgraded to a particular terminal Level.
+type colorSpec struct {
+	target    string // "fg" or "bg"
+	truecolor bool
+	r, g, b   int
+	palette   int
+}
+
+// parseRichToken recognizes the truecolor, 256-palette and named-color
+// token forms accepted inside a @{} block. matched is false when token
+// isn't one of these forms, in which case it should be handled as legacy
+// shortcode characters instead.
+func parseRichToken(token string) (spec colorSpec, matched bool, err error) {
+	if m := hexColorRE.FindStringSubmatch(token); m != nil {
+		return hexSpec(m[1], m[2])
+	}
+	if m := paletteRE.FindStringSubmatch(token); m != nil {
+		var idx int
+		if idx, err = strconv.Atoi(m[2]); err != nil || idx > 255 {
+			err = fmt.Errorf("Wrong color syntax: %s", token)
+			return
+		}
+		return colorSpec{target: target(m[1]), palette: idx}, true, nil
+	}
+	if m := namedRE.FindStringSubmatch(token); m != nil {
+		namedColorsMu.RLock()
+		hex, ok := namedColors[m[2]]
+		namedColorsMu.RUnlock()
+		if !ok {
+			err = fmt.Errorf("Unknown named color: %s", m[2])
+			return
+		}
+		return hexSpec(m[1], hex)
+	}
+	return colorSpec{}, false, nil
+}
+
+// target normalizes an optional "fg"/"bg" prefix match, defaulting to "fg".
+func target(prefix string) string {
+	if prefix == "bg" {
+		return "bg"
+	}
+	return "fg"
+}
+
+func hexSpec(prefix, hex string) (colorSpec, bool, error) {
+	r, g, b, err := parseHex(hex)
+	if err != nil {
+		return colorSpec{}, true, err
+	}
+	return colorSpec{target: target(prefix), truecolor: true, r: r, g: g, b: b}, true, nil
+}
+
+// parseHex parses a "#rrggbb" or "rrggbb" string into its components.
+func parseHex(hex string) (r, g, b int, err error) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		err = fmt.Errorf("Wrong color syntax: #%s", hex)
+		return
+	}
+	var v int64
+	if v, err = strconv.ParseInt(hex, 16, 32); err != nil {
+		err = fmt.Errorf("Wrong color syntax: #%s", hex)
+		return
+	}
+	r = int(v>>16) & 0xff
+	g = int(v>>8) & 0xff
+	b = int(v) & 0xff
+	return
+}
@@ -0,0 +1,100 @@
+package color
+
+import "testing"
+
+func TestParseHex(t *testing.T) {
+	cases := []struct {
+		in      string
+		r, g, b int
+		wantErr bool
+	}{
+		{in: "#ff8800", r: 0xff, g: 0x88, b: 0x00},
+		{in: "003366", r: 0x00, g: 0x33, b: 0x66},
+		{in: "#fff", wantErr: true},
+		{in: "#gggggg", wantErr: true},
+	}
+	for _, c := range cases {
+		r, g, b, err := parseHex(c.in)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseHex(%q): expected error, got none", c.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseHex(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if r != c.r || g != c.g || b != c.b {
+			t.Errorf("parseHex(%q) = %d,%d,%d want %d,%d,%d", c.in, r, g, b, c.r, c.g, c.b)
+		}
+	}
+}
+
+func TestParseRichToken(t *testing.T) {
+	if err := RegisterNamed("warning", "#ffaa00"); err != nil {
+		t.Fatalf("RegisterNamed: %v", err)
+	}
+
+	cases := []struct {
+		token   string
+		matched bool
+		spec    colorSpec
+		wantErr bool
+	}{
+		{token: "#ff8800", matched: true, spec: colorSpec{target: "fg", truecolor: true, r: 0xff, g: 0x88, b: 0}},
+		{token: "bg:#003366", matched: true, spec: colorSpec{target: "bg", truecolor: true, r: 0, g: 0x33, b: 0x66}},
+		{token: "fg:214", matched: true, spec: colorSpec{target: "fg", palette: 214}},
+		{token: "bg:238", matched: true, spec: colorSpec{target: "bg", palette: 238}},
+		{token: "@warning", matched: true, spec: colorSpec{target: "fg", truecolor: true, r: 0xff, g: 0xaa, b: 0}},
+		{token: "@unknown", matched: false, wantErr: true},
+		{token: "bK", matched: false},
+		{token: "fg:999", matched: false, wantErr: true},
+	}
+	for _, c := range cases {
+		spec, matched, err := parseRichToken(c.token)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("parseRichToken(%q): expected error, got none", c.token)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRichToken(%q): unexpected error: %v", c.token, err)
+			continue
+		}
+		if matched != c.matched {
+			t.Errorf("parseRichToken(%q) matched = %v want %v", c.token, matched, c.matched)
+			continue
+		}
+		if matched && spec != c.spec {
+			t.Errorf("parseRichToken(%q) = %+v want %+v", c.token, spec, c.spec)
+		}
+	}
+}
+
+func TestColorizeRichTokens(t *testing.T) {
+	defer levelOverride.Store(nil)
+	l := LevelTrueColor
+	levelOverride.Store(&l)
+
+	cases := []struct {
+		in   string
+		want string
+	}{
+		{in: "#ff8800", want: "\033[38;2;255;136;0m"},
+		{in: "bg:#003366", want: "\033[48;2;0;51;102m"},
+		{in: "fg:214", want: "\033[38;5;214m"},
+		{in: "bg:238", want: "\033[48;5;238m"},
+	}
+	for _, c := range cases {
+		got, err := colorize(c.in, true)
+		if err != nil {
+			t.Errorf("colorize(%q): unexpected error: %v", c.in, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("colorize(%q) = %q want %q", c.in, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package color
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Attribute is a single SGR attribute or color value usable with New and
+// Style.Add. The values mirror the single-letter shortcode syntax accepted
+// by @{}.
+type Attribute int
+
+const (
+	ResetAttr Attribute = 0
+	Bold      Attribute = 1
+	Dim       Attribute = 2
+	Italic    Attribute = 3
+	Underline Attribute = 4
+	Blink     Attribute = 5
+	FastBlink Attribute = 6
+	Reverse   Attribute = 7
+	Hide      Attribute = 8
+
+	FgBlack   Attribute = 30
+	FgRed     Attribute = 31
+	FgGreen   Attribute = 32
+	FgYellow  Attribute = 33
+	FgBlue    Attribute = 34
+	FgMagenta Attribute = 35
+	FgCyan    Attribute = 36
+	FgWhite   Attribute = 37
+	FgDefault Attribute = 39
+
+	BgBlack   Attribute = 40
+	BgRed     Attribute = 41
+	BgGreen   Attribute = 42
+	BgYellow  Attribute = 43
+	BgBlue    Attribute = 44
+	BgMagenta Attribute = 45
+	BgCyan    Attribute = 46
+	BgWhite   Attribute = 47
+	BgDefault Attribute = 49
+)
+
+// Style is a reusable colorizer built from a fixed set of Attributes, for
+// callers that want to pre-build their color once instead of re-parsing a
+// @{} string on every call:
+//
+//      s := color.New(color.FgRed, color.Bold)
+//      s.Println("error:", err)
+//
+// A Style's escape sequence is compiled once, on first use, and cached, so
+// repeated calls (e.g. from a hot logging path) skip the parsing compile()
+// does for the @{} syntax. A Style is safe for concurrent use.
+type Style struct {
+	mu    sync.Mutex
+	attrs []Attribute
+	code  string
+}
+
+// New creates a Style from attrs.
+func New(attrs ...Attribute) *Style {
+	s := &Style{}
+	s.Add(attrs...)
+	return s
+}
+
+// Add appends attrs to the Style and returns it, so calls can be chained.
+func (s *Style) Add(attrs ...Attribute) *Style {
+	s.mu.Lock()
+	s.attrs = append(s.attrs, attrs...)
+	s.code = ""
+	s.mu.Unlock()
+	return s
+}
+
+// compiled returns the cached escape sequence for the Style, computing and
+// caching it on first use. It shares legacySGR with colorize so the two
+// color-code builders in this package can't drift apart.
+func (s *Style) compiled() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.code == "" {
+		attr, fg, bg := 0, 39, 49
+		for _, a := range s.attrs {
+			switch {
+			case 0 <= a && a <= 8:
+				attr = int(a)
+			case 30 <= a && a <= 37:
+				fg = int(a)
+			case 40 <= a && a <= 47:
+				bg = int(a)
+			}
+		}
+		s.code = legacySGR(attr, fg, bg)
+	}
+	return s.code
+}
+
+// wrapSlice prepends the Style's escape code and appends ResetCode to a,
+// unless enabled is false, in which case a is returned untouched.
+func (s *Style) wrapSlice(enabled bool, a []interface{}) []interface{} {
+	if !enabled {
+		return a
+	}
+	wrapped := make([]interface{}, 0, len(a)+2)
+	wrapped = append(wrapped, s.compiled())
+	wrapped = append(wrapped, a...)
+	wrapped = append(wrapped, ResetCode)
+	return wrapped
+}
+
+// wrapFormat brackets format with the Style's escape code and ResetCode,
+// unless enabled is false, in which case format is returned untouched.
+func (s *Style) wrapFormat(enabled bool, format string) string {
+	if !enabled {
+		return format
+	}
+	return s.compiled() + format + ResetCode
+}
+
+// Sprint is similar to fmt.Sprint, coloring the result with the Style.
+func (s *Style) Sprint(a ...interface{}) string {
+	return fmt.Sprint(s.wrapSlice(willColorize(nil), a)...)
+}
+
+// Sprintln is similar to fmt.Sprintln, coloring the result with the Style.
+func (s *Style) Sprintln(a ...interface{}) string {
+	return fmt.Sprintln(s.wrapSlice(willColorize(nil), a)...)
+}
+
+// Sprintf is similar to fmt.Sprintf, coloring the result with the Style.
+func (s *Style) Sprintf(format string, a ...interface{}) string {
+	return fmt.Sprintf(s.wrapFormat(willColorize(nil), format), a...)
+}
+
+// Print is similar to fmt.Print, coloring the output with the Style.
+func (s *Style) Print(a ...interface{}) (int, error) {
+	return fmt.Fprint(stdout, s.wrapSlice(willColorize(os.Stdout), a)...)
+}
+
+// Println is similar to fmt.Println, coloring the output with the Style.
+func (s *Style) Println(a ...interface{}) (int, error) {
+	return fmt.Fprintln(stdout, s.wrapSlice(willColorize(os.Stdout), a)...)
+}
+
+// Printf is similar to fmt.Printf, coloring the output with the Style.
+func (s *Style) Printf(format string, a ...interface{}) (int, error) {
+	return fmt.Fprintf(stdout, s.wrapFormat(willColorize(os.Stdout), format), a...)
+}
+
+// Fprint is similar to fmt.Fprint, coloring the output with the Style.
+func (s *Style) Fprint(w io.Writer, a ...interface{}) (int, error) {
+	return fmt.Fprint(colorable(w), s.wrapSlice(willColorize(w), a)...)
+}
+
+// Fprintln is similar to fmt.Fprintln, coloring the output with the Style.
+func (s *Style) Fprintln(w io.Writer, a ...interface{}) (int, error) {
+	return fmt.Fprintln(colorable(w), s.wrapSlice(willColorize(w), a)...)
+}
+
+// Fprintf is similar to fmt.Fprintf, coloring the output with the Style.
+func (s *Style) Fprintf(w io.Writer, format string, a ...interface{}) (int, error) {
+	return fmt.Fprintf(colorable(w), s.wrapFormat(willColorize(w), format), a...)
+}
+
+// SprintFunc returns a function equivalent to Style.Sprint, for passing
+// around wherever a func(...interface{}) string is expected.
+func (s *Style) SprintFunc() func(a ...interface{}) string {
+	return s.Sprint
+}
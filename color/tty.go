@@ -0,0 +1,8 @@
+package color
+
+import "os"
+
+// stdout is the destination used by Print, Println and Printf. It is
+// wrapped with colorable on platforms where the raw file handle can't
+// interpret ANSI escape codes natively.
+var stdout = colorable(os.Stdout)
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly,!windows
+
+package color
+
+// isTerminal always reports false on platforms we don't have a terminal
+// detection strategy for, so output is treated as non-interactive.
+func isTerminal(fd uintptr) bool {
+	return false
+}
@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package color
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32           = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode = kernel32.NewProc("GetConsoleMode")
+)
+
+// isTerminal reports whether fd refers to a console device.
+func isTerminal(fd uintptr) bool {
+	var mode uint32
+	ret, _, _ := procGetConsoleMode.Call(fd, uintptr(unsafe.Pointer(&mode)))
+	return ret != 0
+}
@@ -0,0 +1,113 @@
+package color
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// lineWriter colorizes each line written through it, splitting on '\n' so
+// that a color code opened for one line is always reset before the next,
+// even when callers make several partial writes per line (as
+// io.Copy/bufio.Scanner readers from a subprocess pipe typically do).
+type lineWriter struct {
+	mu     sync.Mutex
+	raw    io.Writer // original destination, used for terminal detection
+	w      io.Writer // colorable-wrapped destination actually written to
+	prefix string
+	spec   string
+	buf    bytes.Buffer
+}
+
+// NewWriter returns an io.WriteCloser that paints every line written
+// through it with spec (the same syntax Colorize accepts) before
+// forwarding it to w, resetting the style at the end of each line so color
+// never bleeds into surrounding output. It's handy for coloring a
+// subprocess's output stream without touching the process itself, e.g.:
+//
+//      stderr := color.NewWriter(os.Stderr, "r")
+//      cmd.Stderr = stderr
+//      err := cmd.Run()
+//      stderr.Close()
+//
+// Close flushes any trailing partial line that never ended in '\n' (common
+// for a process's last write); callers should call it once the writer is
+// done being written to. Like the rest of this package, coloring is
+// skipped automatically when w isn't a terminal, or when disabled via
+// NO_COLOR or Disable. NewWriter panics if spec isn't valid color syntax.
+// The returned writer is safe for concurrent use.
+func NewWriter(w io.Writer, spec string) io.WriteCloser {
+	return newLineWriter(w, spec, "")
+}
+
+// NewPrefixWriter is like NewWriter, but also writes prefix in front of the
+// color code on every line, e.g. to tag output from multiple sources.
+func NewPrefixWriter(w io.Writer, spec, prefix string) io.WriteCloser {
+	return newLineWriter(w, spec, prefix)
+}
+
+func newLineWriter(w io.Writer, spec, prefix string) *lineWriter {
+	if _, err := Colorize(spec); err != nil {
+		panic(err)
+	}
+	return &lineWriter{raw: w, w: colorable(w), prefix: prefix, spec: spec}
+}
+
+func (lw *lineWriter) Write(p []byte) (n int, err error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	n = len(p)
+	code, enabled := lw.codeLocked()
+	lw.buf.Write(p)
+	for {
+		line, rerr := lw.buf.ReadString('\n')
+		if rerr != nil {
+			// No complete line yet: push the partial line back and wait
+			// for the rest of it to arrive in a later Write.
+			lw.buf.Reset()
+			lw.buf.WriteString(line)
+			return
+		}
+		if err = lw.writeLineLocked(line[:len(line)-1], code, enabled); err != nil {
+			return
+		}
+	}
+}
+
+// Close flushes any trailing partial line left in the buffer (one that
+// never ended in '\n') and emits it like a complete line. It doesn't close
+// the underlying writer, which the caller still owns.
+func (lw *lineWriter) Close() error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.buf.Len() == 0 {
+		return nil
+	}
+	code, enabled := lw.codeLocked()
+	line := lw.buf.String()
+	lw.buf.Reset()
+	return lw.writeLineLocked(line, code, enabled)
+}
+
+// codeLocked computes the current escape code and whether it should be
+// applied. It's recomputed on every call, not cached at construction, so a
+// change in $TERM/Level or Disable between writes is always honored. Must
+// be called with lw.mu held.
+func (lw *lineWriter) codeLocked() (code string, enabled bool) {
+	code, _ = Colorize(lw.spec)
+	return code, shouldColorize(lw.raw) && code != ""
+}
+
+// writeLineLocked emits a single line, with color if enabled. Must be
+// called with lw.mu held.
+func (lw *lineWriter) writeLineLocked(line, code string, enabled bool) (err error) {
+	if enabled {
+		_, err = fmt.Fprintf(lw.w, "%s%s%s%s\n", lw.prefix, code, line, ResetCode)
+	} else {
+		_, err = fmt.Fprintf(lw.w, "%s%s\n", lw.prefix, line)
+	}
+	return
+}
@@ -0,0 +1,65 @@
+package color
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestLineWriterBuffersPartialLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "r")
+	w.Write([]byte("hello"))
+	w.Write([]byte(" world\nsecond line"))
+
+	if got, want := buf.String(), "hello world\n"; got != want {
+		t.Fatalf("after partial writes: got %q want %q", got, want)
+	}
+}
+
+func TestLineWriterCloseFlushesTrailingLine(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "r")
+	w.Write([]byte("hello world\nsecond line"))
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got, want := buf.String(), "hello world\nsecond line\n"; got != want {
+		t.Fatalf("after Close: got %q want %q", got, want)
+	}
+}
+
+func TestLineWriterCloseNoTrailingData(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "r")
+	w.Write([]byte("hello\n"))
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got, want := buf.String(), "hello\n"; got != want {
+		t.Fatalf("Close with no pending data changed output: got %q want %q", got, want)
+	}
+}
+
+func TestLineWriterConcurrentWrites(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "r")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			fmt.Fprintf(w, "line %d\n", i)
+		}(i)
+	}
+	wg.Wait()
+
+	lines := bytes.Count(buf.Bytes(), []byte("\n"))
+	if lines != 50 {
+		t.Fatalf("got %d complete lines, want 50", lines)
+	}
+}